@@ -2,196 +2,288 @@ package main
 
 import (
 	"bufio"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/jupj/hledger-fmt/journal"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
-// sep defines the separator line for ledger file
-// anything below this line will be replaced by the output of `hledger print`
-const sep = "; :::Transactions:::"
+// journalExts lists the file extensions recognized when a directory is
+// given on the command line.
+var journalExts = map[string]bool{
+	".journal":   true,
+	".hledger":   true,
+	".ledger":    true,
+	".timeclock": true,
+	".timedot":   true,
+}
 
-var (
-	reTransaction = regexp.MustCompile(`^\d`)
-	rePosting     = regexp.MustCompile(`^\s+\S`)
-	reInclude     = regexp.MustCompile(`^include `)
-)
+// formatterFor picks the Formatter matching path's extension: a timeclock
+// or timedot formatter for their respective extensions, or the native
+// journal.DefaultFormatter for a plain hledger journal.
+func formatterFor(path string, opts runOptions) journal.Formatter {
+	switch filepath.Ext(path) {
+	case ".timeclock":
+		return journal.TimeclockFormatter{Autoclose: opts.timeclockAutoclose}
+	case ".timedot":
+		return journal.TimedotFormatter{Style: opts.timedotStyle}
+	default:
+		return journal.DefaultFormatter{}
+	}
+}
 
-// parseJournal splits the journal read from r into preamble and transactions
-// return an error if
-// - ledgerFile has no separator line
-// - ledgerFile has more than one separator line
-// - lines below the separator line are anything else than transactions
-func parseJournal(r io.Reader) (preamble []string, transactions []string, err error) {
-	// Read lines up to sep
-	scan := bufio.NewScanner(r)
-	foundSep := false
-	lineNr := 0
-	for scan.Scan() {
-		lineNr++
-		if scan.Text() == sep {
-			foundSep = true
-			break
-		}
-		preamble = append(preamble, scan.Text())
+var reIncludeLine = regexp.MustCompile(`^include\s+(.+)$`)
+
+// writeFile atomically replaces ledgerFile's contents with content, using
+// the same tmpfile+rename dance as before.
+func writeFile(ledgerFile string, content string) error {
+	tmpfile, err := ioutil.TempFile(
+		filepath.Dir(ledgerFile),
+		filepath.Base(ledgerFile)+".tmp_")
+	if err != nil {
+		return err
 	}
 
-	if !foundSep {
-		return nil, nil, errors.New("ledger file contains no transaction separator")
+	if _, err := io.WriteString(tmpfile, content); err != nil {
+		tmpfile.Close()
+		return err
+	}
+	if err := tmpfile.Close(); err != nil {
+		return err
 	}
 
-	// Check that anything after this is only valid transactions
-	for scan.Scan() {
-		lineNr++
-		if scan.Text() == sep {
-			return nil, nil, errors.New("ledger file contains multiple transaction separators")
-		}
+	return os.Rename(tmpfile.Name(), ledgerFile)
+}
 
-		transactions = append(transactions, scan.Text())
+// runOptions holds the gofmt-style flags controlling how a formatted
+// journal file is reported or applied.
+type runOptions struct {
+	list  bool // -l: print the names of files that would change
+	diff  bool // -d: print a unified diff of the changes
+	write bool // -w: write the result back to the file
 
-		// Allow empty lines
-		if strings.TrimSpace(scan.Text()) == "" {
-			continue
-		}
+	timeclockAutoclose journal.TimeclockAutoclose // --timeclock-autoclose
+	timedotStyle       journal.TimedotStyle       // --timedot-style
+}
 
-		// Date - starts transaction
-		if reTransaction.Match(scan.Bytes()) {
-			continue
-		}
+// run formats ledgerFile according to opts, reporting to stdout as
+// requested. It returns changed=true if the file's canonical formatting
+// differs from what's on disk.
+func run(ledgerFile string, opts runOptions) (changed bool, err error) {
+	src, err := os.Open(ledgerFile)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
 
-		// posting lines - must be indented
-		if rePosting.Match(scan.Bytes()) {
-			continue
-		}
+	original, err := ioutil.ReadAll(src)
+	if err != nil {
+		return false, err
+	}
 
-		return nil, nil, fmt.Errorf("ledger file contains unexpected line %d in transactions:\n%s", lineNr, scan.Text())
+	var buf strings.Builder
+	if err := formatterFor(ledgerFile, opts).Format(strings.NewReader(string(original)), &buf); err != nil {
+		return false, err
 	}
+	formatted := buf.String()
+
+	changed = formatted != string(original)
 
-	if err := scan.Err(); err != nil {
-		return nil, nil, scan.Err()
+	switch {
+	case opts.list:
+		if changed {
+			fmt.Println(ledgerFile)
+		}
+	case opts.diff:
+		if changed {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(original)),
+				B:        difflib.SplitLines(formatted),
+				FromFile: ledgerFile,
+				ToFile:   ledgerFile + " (formatted)",
+				Context:  3,
+			})
+			if err != nil {
+				return changed, err
+			}
+			fmt.Print(diff)
+		}
+	case opts.write:
+		// nothing more to print; handled below
+	default:
+		fmt.Print(formatted)
 	}
-	return preamble, transactions, nil
-}
 
-// formatTransactions reads a hledger journal from r, formats all transactions
-// under the separator line, and writes the formatted journal to w
-func formatTransactions(w io.Writer, r io.Reader) error {
-	preamble, transactions, err := parseJournal(r)
-	if err != nil {
-		return err
+	if opts.write && changed {
+		if err := writeFile(ledgerFile, formatted); err != nil {
+			return changed, err
+		}
 	}
 
-	// Write preamble "as is" to w
-	fmt.Fprintln(w, strings.Join(preamble, "\n"))
-	fmt.Fprintln(w, sep)
-	fmt.Fprintln(w)
+	return changed, nil
+}
 
-	// run `hledger print` to format the transactions in ledgerFile
-	cmd := exec.Command("hledger", "-f", "-", "--ignore-assertions", "print")
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return err
-	}
-	cmd.Stderr = os.Stderr
-
-	go func() {
-		defer stdin.Close()
-		// rewrite preamble to comment out include statements and transactions
-		inTransaction := false
-		const comment = "; "
-		for _, line := range preamble {
-			switch {
-			case strings.TrimSpace(line) == "":
-				inTransaction = false
-			case reTransaction.MatchString(line):
-				inTransaction = true
-				fallthrough
-			case reInclude.MatchString(line), inTransaction && rePosting.MatchString(line):
-				fmt.Fprint(stdin, comment)
-			}
+// collectFiles expands paths into a flat list of journal files, walking
+// directories for files with an extension listed in journalExts.
+func collectFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
 
-			fmt.Fprintln(stdin, line)
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
 		}
-		fmt.Fprintln(stdin, sep)
-		for _, line := range transactions {
-			fmt.Fprintln(stdin, line)
+
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if journalExts[filepath.Ext(p)] {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-	}()
+	}
+	return files, nil
+}
 
-	// Get formatted transactions
-	journal, err := cmd.Output()
-	if err != nil {
-		return err
+// expandIncludes walks the include-directive tree rooted at seed, returning
+// seed plus every file it (transitively) includes, each listed once. Paths
+// are deduplicated on their absolute form so cyclic includes terminate.
+// When noRecurse is true, seed is returned unchanged.
+func expandIncludes(seed []string, noRecurse bool) ([]string, error) {
+	if noRecurse {
+		return seed, nil
 	}
 
-	// Remove trailing empty lines from transactions
-	journal = regexp.MustCompile(`\n+$`).ReplaceAll(journal, []byte("\n"))
+	visited := map[string]bool{}
+	var files []string
+	queue := append([]string{}, seed...)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
 
-	// Write transactions to w
-	if _, err := w.Write(journal); err != nil {
-		return err
-	}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if visited[abs] {
+			continue
+		}
+		visited[abs] = true
+		files = append(files, path)
 
-	return nil
+		includes, err := findIncludes(path)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, includes...)
+	}
+	return files, nil
 }
 
-func run(ledgerFile string) error {
-	// read the journal file to format
-	journal, err := os.Open(ledgerFile)
+// findIncludes returns the paths named by `include` directives in path,
+// resolved relative to path's directory.
+func findIncludes(path string) ([]string, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer f.Close()
 
-	// Create tempfile - write the formatted journal here
-	tmpfile, err := ioutil.TempFile(
-		filepath.Dir(ledgerFile),
-		filepath.Base(ledgerFile)+".tmp_")
-	if err != nil {
-		return err
+	var includes []string
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		m := reIncludeLine.FindStringSubmatch(scan.Text())
+		if m == nil {
+			continue
+		}
+		includes = append(includes, filepath.Join(filepath.Dir(path), strings.TrimSpace(m[1])))
 	}
+	return includes, scan.Err()
+}
 
-	// Format journal to tmpfile
-	if err := formatTransactions(tmpfile, journal); err != nil {
-		tmpfile.Close()
-		journal.Close()
-		return err
+func main() {
+	defaultLedgerFile := os.Getenv("LEDGER_FILE")
+	if defaultLedgerFile == "" {
+		defaultLedgerFile = filepath.Join(os.Getenv("HOME"), ".hledger.journal")
 	}
 
-	// Close files, return error if any close fails
-	if err := tmpfile.Close(); err != nil {
-		journal.Close()
-		return err
+	ledgerFile := flag.String("f", "", "hledger journal file (deprecated: use positional file/directory arguments)")
+	list := flag.Bool("l", false, "list files whose formatting differs from hledger-fmt's")
+	diff := flag.Bool("d", false, "display diffs of formatting changes")
+	write := flag.Bool("w", false, "write result to (source) file instead of stdout")
+	noRecurse := flag.Bool("no-recurse", false, "don't follow include directives")
+	timeclockAutoclose := flag.String("timeclock-autoclose", string(journal.TimeclockAutocloseEOF),
+		"how to close an unclosed timeclock session: eof, midnight or none")
+	timedotStyle := flag.String("timedot-style", string(journal.TimedotStyleDots),
+		"how to render timedot quantities: dots or decimal")
+	flag.Parse()
+
+	paths := flag.Args()
+	if *ledgerFile != "" {
+		paths = append(paths, *ledgerFile)
 	}
-	if err := journal.Close(); err != nil {
-		return err
+	if len(paths) == 0 {
+		paths = []string{defaultLedgerFile}
 	}
 
-	// Replace ledgerFile with the newly formatted tmpfile
-	if err := os.Rename(tmpfile.Name(), ledgerFile); err != nil {
-		return err
+	files, err := collectFiles(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
 	}
 
-	return nil
-}
+	files, err = expandIncludes(files, *noRecurse)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
-func main() {
-	ledgerFile := os.Getenv("LEDGER_FILE")
-	if ledgerFile == "" {
-		ledgerFile = filepath.Join(os.Getenv("HOME"), ".hledger.journal")
+	opts := runOptions{
+		list:               *list,
+		diff:               *diff,
+		write:              *write,
+		timeclockAutoclose: journal.TimeclockAutoclose(*timeclockAutoclose),
+		timedotStyle:       journal.TimedotStyle(*timedotStyle),
 	}
 
-	flag.StringVar(&ledgerFile, "f", ledgerFile, "hledger journal file")
-	flag.Parse()
+	anyChanged := false
+	for _, file := range files {
+		changed, err := run(file, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		anyChanged = anyChanged || changed
+	}
 
-	if err := run(ledgerFile); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+	os.Exit(exitCode(opts, anyChanged))
+}
+
+// exitCode reports the process exit status for a run of collected files:
+// non-zero when -l or -d is given and at least one file would change, so
+// the tool is usable as a CI/pre-commit check.
+func exitCode(opts runOptions, anyChanged bool) int {
+	if (opts.list || opts.diff) && anyChanged {
+		return 1
 	}
+	return 0
 }
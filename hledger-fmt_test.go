@@ -1,137 +1,284 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
-)
-
-func TestParse(t *testing.T) {
 
-	preamble, transactions, err := parseJournal(strings.NewReader(`
-D 10,00 €
+	"github.com/jupj/hledger-fmt/journal"
+)
 
-2021-01-01 Pre-transaction
-    expense          7,90
-    income                -7,90
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// everything written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
 
-; :::Transactions:::
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
 
-2021-01-03 Groceries
-    expense           135,43 €
-    income       -135,43
-`))
+	fn()
 
+	w.Close()
+	out, err := ioutil.ReadAll(r)
 	if err != nil {
 		t.Fatal(err)
 	}
+	return string(out)
+}
+
+func TestFormatterFor(t *testing.T) {
+	opts := runOptions{
+		timeclockAutoclose: journal.TimeclockAutocloseEOF,
+		timedotStyle:       journal.TimedotStyleDots,
+	}
 
-	const expectedPreamble = `
-D 10,00 €
+	cases := []struct {
+		path string
+		want journal.Formatter
+	}{
+		{"foo.journal", journal.DefaultFormatter{}},
+		{"foo.timeclock", journal.TimeclockFormatter{Autoclose: journal.TimeclockAutocloseEOF}},
+		{"foo.timedot", journal.TimedotFormatter{Style: journal.TimedotStyleDots}},
+	}
+
+	for _, c := range cases {
+		if got := formatterFor(c.path, opts); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("formatterFor(%q) = %#v, want %#v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestExpandIncludes(t *testing.T) {
+	dir := t.TempDir()
 
-2021-01-01 Pre-transaction
-    expense          7,90
-    income                -7,90
-`
+	root := filepath.Join(dir, "root.journal")
+	child := filepath.Join(dir, "child.journal")
 
-	got := strings.Join(preamble, "\n")
-	if got != expectedPreamble {
-		t.Errorf("Got preamble:\n%q\nExpected:\n%q\n", got, expectedPreamble)
+	if err := ioutil.WriteFile(root, []byte("include child.journal\ninclude child.journal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(child, []byte("include root.journal\n"), 0o644); err != nil {
+		t.Fatal(err)
 	}
 
-	const expectedTransactions = `
-2021-01-03 Groceries
-    expense           135,43 €
-    income       -135,43`
-	got = strings.Join(transactions, "\n")
-	if got != expectedTransactions {
-		t.Errorf("Got transactionis:\n%q\nExpected:\n%q\n", got, expectedTransactions)
+	files, err := expandIncludes([]string{root}, false)
+	if err != nil {
+		t.Fatal(err)
 	}
 
+	want := []string{root, child}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expandIncludes = %v, want %v", files, want)
+	}
 }
 
-func TestFormat(t *testing.T) {
+func TestExpandIncludesNoRecurse(t *testing.T) {
+	dir := t.TempDir()
 
-	const input = `
-D 10,00 €
+	root := filepath.Join(dir, "root.journal")
+	if err := ioutil.WriteFile(root, []byte("include child.journal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-2021-01-01 Pre-transaction
-	expense          7,90
-	income                -7,90
+	files, err := expandIncludes([]string{root}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-; :::Transactions:::
+	want := []string{root}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("expandIncludes = %v, want %v", files, want)
+	}
+}
 
-2021-01-03 Groceries
-	expense           135,43 €
-	income       -135,43
-`
+func defaultRunOptions() runOptions {
+	return runOptions{
+		timeclockAutoclose: journal.TimeclockAutocloseEOF,
+		timedotStyle:       journal.TimedotStyleDots,
+	}
+}
 
-	var buf strings.Builder
-	if err := formatTransactions(&buf, strings.NewReader(input)); err != nil {
+func TestRunDefaultPrintsWithoutWriting(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.journal")
+	const original = "2021-01-03 Groceries\n    a    1\n    b\n"
+	if err := ioutil.WriteFile(file, []byte(original), 0o644); err != nil {
 		t.Fatal(err)
 	}
 
-	got := buf.String()
-	const expected = `
-D 10,00 €
+	var changed bool
+	var err error
+	out := captureStdout(t, func() {
+		changed, err = run(file, defaultRunOptions())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected changed = true")
+	}
+	if out == "" {
+		t.Error("expected formatted output on stdout")
+	}
 
-2021-01-01 Pre-transaction
-	expense          7,90
-	income                -7,90
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != original {
+		t.Errorf("file was modified without -w: %q", got)
+	}
+}
 
-; :::Transactions:::
+func TestRunWrite(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.journal")
+	const original = "2021-01-03 Groceries\n    a    1\n    b\n"
+	if err := ioutil.WriteFile(file, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-2021-01-03 Groceries
-    expense        135,43 €
-    income        -135,43 €
-`
-	if got != expected {
-		t.Errorf("Got journal:\n%q\nExpected:\n%q\n", got, expected)
+	opts := defaultRunOptions()
+	opts.write = true
+	changed, err := run(file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected changed = true")
 	}
 
+	got, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) == original {
+		t.Error("expected file to be rewritten with canonical formatting")
+	}
 }
 
-func TestAssertions(t *testing.T) {
-	// balance assertions might be inconsistent due to not including transactions from other files.
-	// => hledger-fmt should ignore balance assertions
-	const input = `
-D 10,00 €
+func TestRunList(t *testing.T) {
+	dir := t.TempDir()
+	changedFile := filepath.Join(dir, "changed.journal")
+	cleanFile := filepath.Join(dir, "clean.journal")
 
-2021-01-01 Pre-transaction
-	expense          7,90 = 100,00
-	income                -7,90
-
-include expenses.journal
+	if err := ioutil.WriteFile(changedFile, []byte("2021-01-03 Groceries\n    a    1\n    b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-; :::Transactions:::
+	var formatted strings.Builder
+	if err := (journal.DefaultFormatter{}).Format(strings.NewReader("2021-01-03 Groceries\n  a  1\n  b\n"), &formatted); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cleanFile, []byte(formatted.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-2021-01-03 Groceries
-	expense           135,43 € = 1000,00
-	income       -135,43
-`
+	opts := defaultRunOptions()
+	opts.list = true
+
+	out := captureStdout(t, func() {
+		if changed, err := run(changedFile, opts); err != nil {
+			t.Fatal(err)
+		} else if !changed {
+			t.Fatal("expected changedFile to differ from canonical formatting")
+		}
+		if changed, err := run(cleanFile, opts); err != nil {
+			t.Fatal(err)
+		} else if changed {
+			t.Error("expected cleanFile to already be canonically formatted")
+		}
+	})
+
+	if strings.TrimSpace(out) != changedFile {
+		t.Errorf("-l output = %q, want %q", out, changedFile)
+	}
+}
 
-	var buf strings.Builder
-	if err := formatTransactions(&buf, strings.NewReader(input)); err != nil {
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.journal")
+	if err := ioutil.WriteFile(file, []byte("2021-01-03 Groceries\n    a    1\n    b\n"), 0o644); err != nil {
 		t.Fatal(err)
 	}
 
-	got := buf.String()
-	const expected = `
-D 10,00 €
+	opts := defaultRunOptions()
+	opts.diff = true
+
+	out := captureStdout(t, func() {
+		changed, err := run(file, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !changed {
+			t.Error("expected changed = true")
+		}
+	})
+
+	if !strings.HasPrefix(out, "---") || !strings.Contains(out, "+++") {
+		t.Errorf("expected a unified diff, got:\n%s", out)
+	}
+}
 
-2021-01-01 Pre-transaction
-	expense          7,90 = 100,00
-	income                -7,90
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		opts       runOptions
+		anyChanged bool
+		want       int
+	}{
+		{runOptions{}, true, 0},
+		{runOptions{write: true}, true, 0},
+		{runOptions{list: true}, false, 0},
+		{runOptions{list: true}, true, 1},
+		{runOptions{diff: true}, true, 1},
+	}
+
+	for _, c := range cases {
+		if got := exitCode(c.opts, c.anyChanged); got != c.want {
+			t.Errorf("exitCode(%+v, %v) = %d, want %d", c.opts, c.anyChanged, got, c.want)
+		}
+	}
+}
 
-include expenses.journal
+func TestCollectFilesWalksDirectory(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
 
-; :::Transactions:::
+	want := []string{
+		filepath.Join(dir, "a.journal"),
+		filepath.Join(dir, "b.timeclock"),
+		filepath.Join(sub, "c.ledger"),
+	}
+	for _, f := range want {
+		if err := ioutil.WriteFile(f, []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A non-journal file in the tree should be skipped.
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte{}, 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-2021-01-03 Groceries
-    expense        135,43 € = 1000,00 €
-    income        -135,43 €
-`
-	if got != expected {
-		t.Errorf("Got journal:\n%q\nExpected:\n%q\n", got, expected)
+	got, err := collectFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
 	}
 
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectFiles = %v, want %v", got, want)
+	}
 }
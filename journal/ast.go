@@ -0,0 +1,137 @@
+// Package journal implements a parser and pretty-printer for the hledger
+// plain text journal format, modeled on the grammar used by hledger's
+// JournalReader.
+package journal
+
+import "io"
+
+// Journal is the parsed representation of an hledger journal file.
+type Journal struct {
+	// Items holds every directive, transaction, blank line and standalone
+	// comment in the file, in their original order, so Format can
+	// reproduce them verbatim at their original positions.
+	Items []JournalItem
+
+	// Directives and Transactions are the same Directives/Transactions
+	// found in Items, provided as flat slices for callers that don't care
+	// about ordering relative to blank lines and comments.
+	Directives   []Directive
+	Transactions []Transaction
+}
+
+// JournalItem is one element of a Journal, in its original position.
+// Exactly one of Directive, Transaction, Blank or Comment applies.
+type JournalItem struct {
+	Directive   *Directive
+	Transaction *Transaction
+	Blank       bool   // a blank line
+	Comment     string // a standalone "; ..." comment line, verbatim
+}
+
+// DirectiveKind identifies which journal directive a Directive represents.
+type DirectiveKind int
+
+// The directive kinds understood by the parser.
+const (
+	DirectiveInclude DirectiveKind = iota
+	DirectiveAccount
+	DirectiveAlias
+	DirectiveCommodity
+	DirectiveDefaultCommodity // D
+	DirectiveMarketPrice      // P
+	DirectiveDefaultYear      // Y
+)
+
+// Directive is a single journal directive line, e.g. "include ..." or
+// "account ...".
+type Directive struct {
+	Kind DirectiveKind
+	// Args holds the directive's arguments exactly as written, e.g. the
+	// path for "include", or the account name for "account".
+	Args string
+}
+
+// Transaction is a single dated journal entry together with its postings.
+type Transaction struct {
+	Date        string
+	Date2       string // secondary date, empty if not given
+	Status      string // "", "*" or "!"
+	Code        string // contents of (CODE), without the parentheses
+	Description string
+	Note        string // text following "|" in the header, if any
+	Postings    []Posting
+
+	// LeadingComments holds standalone "; ..." comment lines (verbatim,
+	// including the leading ";") that immediately precede this
+	// transaction's header line, with no blank line in between.
+	LeadingComments []string
+	// HeaderComment holds the transaction-level comment, if any: an
+	// inline "; ..." comment on the header line itself, followed by any
+	// indented comment-only lines before the first posting, joined by
+	// "\n". The leading ";" is stripped from each line.
+	HeaderComment string
+}
+
+// Posting is a single account line within a Transaction.
+type Posting struct {
+	Status  string // "", "*" or "!"
+	Account string
+	// Amount is nil for elided postings, i.e. postings that infer their
+	// amount from the other postings in the transaction.
+	Amount *Amount
+	// AssertionOp is "=" or "==" when the posting carries a balance
+	// assertion, and "" otherwise.
+	AssertionOp    string
+	AssertedAmount *Amount
+	// PriceClause holds a unit/total price clause, e.g. "@ $150.00" or
+	// "@@ €92", verbatim as written, or "" if the posting has none. It is
+	// kept rather than parsed: only alignment, not price conversion,
+	// matters here.
+	PriceClause string
+	// TrailingComment holds the posting's inline "; ..." comment and any
+	// indented comment-only lines following it before the next posting,
+	// joined by "\n". The leading ";" is stripped from each line.
+	TrailingComment string
+}
+
+// CommoditySymbol identifies a commodity, e.g. "$", "€" or "USD".
+type CommoditySymbol string
+
+// AmountStyle describes how a commodity's amounts are conventionally
+// written. It is inferred from the first amount seen for the commodity, or
+// overridden by a `commodity` directive.
+type AmountStyle struct {
+	SymbolLeft     bool // true if the symbol precedes the quantity
+	SymbolSpaced   bool // true if the symbol and quantity are separated by a space
+	DecimalChar    rune // '.' or ','
+	DigitGroupChar rune // 0 if absent, else ',', '.' or ' '
+	Precision      int  // number of digits after DecimalChar
+}
+
+// Amount is a quantity paired with a commodity.
+type Amount struct {
+	Commodity CommoditySymbol
+	Quantity  string // numeric literal as written, sign included, no symbol
+	Style     AmountStyle
+}
+
+// Formatter canonicalizes an input file read from r, writing the result to
+// w. DefaultFormatter, TimeclockFormatter and TimedotFormatter all
+// implement it, so future formats (CSV rules, etc.) can plug in the same
+// way; callers typically choose one by the input file's extension.
+type Formatter interface {
+	Format(r io.Reader, w io.Writer) error
+}
+
+// DefaultFormatter formats a plain hledger journal: Parse followed by
+// (*Journal).Format.
+type DefaultFormatter struct{}
+
+// Format implements Formatter.
+func (DefaultFormatter) Format(r io.Reader, w io.Writer) error {
+	j, err := Parse(r)
+	if err != nil {
+		return err
+	}
+	return j.Format(w)
+}
@@ -0,0 +1,315 @@
+package journal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	indent = "  "
+	minGap = "  "
+)
+
+// Format writes j to w, mimicking `hledger print`: two-space indent,
+// a single space between quantity and commodity symbol where the
+// commodity's style separates them (none otherwise), and amounts
+// right-aligned within each transaction so decimal points line up. Blank
+// lines, standalone comments and directive/transaction ordering are
+// reproduced verbatim at their original positions; only the transactions
+// themselves (postings, amounts) are canonicalized.
+func (j *Journal) Format(w io.Writer) error {
+	for _, item := range j.Items {
+		switch {
+		case item.Blank:
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		case item.Comment != "":
+			if _, err := fmt.Fprintln(w, item.Comment); err != nil {
+				return err
+			}
+		case item.Directive != nil:
+			if _, err := fmt.Fprintln(w, formatDirective(*item.Directive)); err != nil {
+				return err
+			}
+		case item.Transaction != nil:
+			if err := formatTransaction(w, *item.Transaction); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatDirective(d Directive) string {
+	switch d.Kind {
+	case DirectiveInclude:
+		return "include " + d.Args
+	case DirectiveAccount:
+		return "account " + d.Args
+	case DirectiveAlias:
+		return "alias " + d.Args
+	case DirectiveCommodity:
+		return "commodity " + d.Args
+	case DirectiveDefaultCommodity:
+		return "D " + d.Args
+	case DirectiveMarketPrice:
+		return "P " + d.Args
+	case DirectiveDefaultYear:
+		return "Y " + d.Args
+	default:
+		return d.Args
+	}
+}
+
+func formatTransaction(w io.Writer, txn Transaction) error {
+	for _, c := range txn.LeadingComments {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+
+	var headerLines []string
+	if txn.HeaderComment != "" {
+		headerLines = strings.Split(txn.HeaderComment, "\n")
+	}
+
+	header := formatHeader(txn)
+	if len(headerLines) > 0 {
+		header += "  ; " + headerLines[0]
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+	for _, c := range headerLines[min(1, len(headerLines)):] {
+		if _, err := fmt.Fprintln(w, indent+"; "+c); err != nil {
+			return err
+		}
+	}
+
+	accountWidth := 0
+	leftWidth := 0
+	rendered := make([]postingRender, len(txn.Postings))
+	accountFields := make([]string, len(txn.Postings))
+	for i, p := range txn.Postings {
+		rendered[i] = renderPosting(p)
+		accountFields[i] = postingAccountField(p)
+		if w := utf8.RuneCountInString(accountFields[i]); w > accountWidth {
+			accountWidth = w
+		}
+		if w := utf8.RuneCountInString(rendered[i].left); w > leftWidth {
+			leftWidth = w
+		}
+	}
+
+	for i, p := range txn.Postings {
+		r := rendered[i]
+		line := indent + padRight(accountFields[i], accountWidth)
+		if r.left == "" && r.right == "" {
+			line = indent + accountFields[i]
+		} else {
+			line += minGap + padLeft(r.left, leftWidth) + r.right
+		}
+
+		var trailingLines []string
+		if p.TrailingComment != "" {
+			trailingLines = strings.Split(p.TrailingComment, "\n")
+			line += "  ; " + trailingLines[0]
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, c := range trailingLines[min(1, len(trailingLines)):] {
+			if _, err := fmt.Fprintln(w, indent+indent+"; "+c); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// min is a small helper kept for Go versions without the builtin min.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// postingAccountField renders a posting's status marker, if any, followed
+// by its account name, e.g. "* expense:groceries".
+func postingAccountField(p Posting) string {
+	if p.Status == "" {
+		return p.Account
+	}
+	return p.Status + " " + p.Account
+}
+
+func formatHeader(txn Transaction) string {
+	var b strings.Builder
+	b.WriteString(txn.Date)
+	if txn.Date2 != "" {
+		b.WriteByte('=')
+		b.WriteString(txn.Date2)
+	}
+	if txn.Status != "" {
+		b.WriteByte(' ')
+		b.WriteString(txn.Status)
+	}
+	if txn.Code != "" {
+		b.WriteString(" (")
+		b.WriteString(txn.Code)
+		b.WriteByte(')')
+	}
+	if txn.Description != "" {
+		b.WriteByte(' ')
+		b.WriteString(txn.Description)
+	}
+	if txn.Note != "" {
+		b.WriteString(" | ")
+		b.WriteString(txn.Note)
+	}
+	return b.String()
+}
+
+// postingRender splits a posting's formatted amount (plus any balance
+// assertion) into a left segment, padded to a common width so that decimal
+// points line up, and a right segment appended verbatim.
+type postingRender struct {
+	left  string
+	right string
+}
+
+func renderPosting(p Posting) postingRender {
+	if p.Amount == nil {
+		return postingRender{}
+	}
+
+	left, right := splitAmount(*p.Amount)
+	if p.PriceClause != "" {
+		right += " " + p.PriceClause
+	}
+	if p.AssertionOp != "" && p.AssertedAmount != nil {
+		assertedLeft, assertedRight := splitAmount(*p.AssertedAmount)
+		right += " " + p.AssertionOp + " " + assertedLeft + assertedRight
+	}
+	return postingRender{left: left, right: right}
+}
+
+// splitAmount renders an amount, returning the part up to and including the
+// integer digits (and any left-side symbol) separately from the decimal
+// point onward (and any right-side symbol), so that callers can align the
+// decimal points of several amounts by padding the left part.
+func splitAmount(a Amount) (left, right string) {
+	number := renderQuantity(a.Quantity, a.Style)
+
+	intPart, decPart := number, ""
+	if i := strings.IndexRune(number, a.Style.DecimalChar); i >= 0 {
+		intPart, decPart = number[:i], number[i:]
+	}
+
+	if a.Commodity == "" {
+		return intPart, decPart
+	}
+
+	symbol := string(a.Commodity)
+	sep := ""
+	if a.Style.SymbolSpaced {
+		sep = " "
+	}
+
+	if a.Style.SymbolLeft {
+		return symbol + sep + intPart, decPart
+	}
+	return intPart, decPart + sep + symbol
+}
+
+// renderQuantity re-renders a numeric literal using style's canonical
+// decimal character, digit-group character and precision.
+func renderQuantity(raw string, style AmountStyle) string {
+	sign := ""
+	digits := raw
+	if strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	lastComma := strings.LastIndexByte(digits, ',')
+	lastDot := strings.LastIndexByte(digits, '.')
+	decimalAt := -1
+	if lastComma > lastDot {
+		decimalAt = lastComma
+	} else if lastDot > lastComma {
+		decimalAt = lastDot
+	}
+
+	intDigits, fracDigits := digits, ""
+	if decimalAt >= 0 {
+		intDigits, fracDigits = digits[:decimalAt], digits[decimalAt+1:]
+	}
+	intDigits = stripNonDigits(intDigits)
+	fracDigits = stripNonDigits(fracDigits)
+
+	precision := style.Precision
+	if len(fracDigits) > precision {
+		precision = len(fracDigits)
+	}
+	for len(fracDigits) < precision {
+		fracDigits += "0"
+	}
+
+	intDigits = groupDigits(intDigits, style.DigitGroupChar)
+
+	var b strings.Builder
+	b.WriteString(sign)
+	b.WriteString(intDigits)
+	if precision > 0 {
+		b.WriteRune(style.DecimalChar)
+		b.WriteString(fracDigits)
+	}
+	return b.String()
+}
+
+func stripNonDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func groupDigits(digits string, groupChar rune) string {
+	if groupChar == 0 || len(digits) <= 3 {
+		return digits
+	}
+
+	var groups []string
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	groups = append([]string{digits}, groups...)
+	return strings.Join(groups, string(groupChar))
+}
+
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+func padLeft(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return strings.Repeat(" ", width-n) + s
+}
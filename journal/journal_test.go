@@ -0,0 +1,236 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	const input = `
+account assets:cash
+
+2021-01-03 * (42) Groceries | paid by card
+    expense:groceries           135,43 €
+    assets:cash       -135,43 €
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(j.Directives) != 1 || j.Directives[0].Kind != DirectiveAccount || j.Directives[0].Args != "assets:cash" {
+		t.Fatalf("unexpected directives: %+v", j.Directives)
+	}
+
+	if len(j.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(j.Transactions))
+	}
+
+	txn := j.Transactions[0]
+	if txn.Date != "2021-01-03" || txn.Status != "*" || txn.Code != "42" ||
+		txn.Description != "Groceries" || txn.Note != "paid by card" {
+		t.Fatalf("unexpected transaction: %+v", txn)
+	}
+	if len(txn.Postings) != 2 {
+		t.Fatalf("expected 2 postings, got %d", len(txn.Postings))
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `
+account assets:cash
+
+2021-01-03 * (42) Groceries | paid by card
+  expense:groceries   135,43 €
+  assets:cash        -135,43 €
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestParseAndFormatPreservesComments(t *testing.T) {
+	const input = `; standalone comment
+
+; leading comment on the transaction
+2021-01-03 Groceries ; header comment
+    ; more header comment
+    expense:groceries   135,43 €
+    assets:cash        -135,43 € ; trailing comment
+    ; more trailing comment
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `; standalone comment
+
+; leading comment on the transaction
+2021-01-03 Groceries  ; header comment
+  ; more header comment
+  expense:groceries   135,43 €
+  assets:cash        -135,43 €  ; trailing comment
+    ; more trailing comment
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestParseAndFormatPreservesStatus(t *testing.T) {
+	const input = `2021-01-03 * Groceries
+    * expense:groceries   135,43 €
+    assets:cash          -135,43 €
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := j.Transactions[0]
+	if txn.Status != "*" || txn.Postings[0].Status != "*" || txn.Postings[1].Status != "" {
+		t.Fatalf("status not parsed: %+v", txn)
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `2021-01-03 * Groceries
+  * expense:groceries   135,43 €
+  assets:cash          -135,43 €
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestParseAndFormatAlignsMultiByteCommoditySymbol(t *testing.T) {
+	const input = `2021-01-03 Test
+    a   €100.00
+    b   5000.00
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `2021-01-03 Test
+  a  €100.00
+  b  5000.00
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestCommodityDirectiveSeedsStyle(t *testing.T) {
+	const input = `commodity $1,000.00
+
+2021-01-03 Test
+    a   $1000
+    b   $2000
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `commodity $1,000.00
+
+2021-01-03 Test
+  a  $1,000.00
+  b  $2,000.00
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestParseAndFormatPriceClause(t *testing.T) {
+	const input = `2021-01-03 Buy stock
+    assets:stock  10 AAPL @ $150.00
+    assets:cash
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := j.Transactions[0].Postings[0].PriceClause; got != "@ $150.00" {
+		t.Fatalf("PriceClause = %q, want %q", got, "@ $150.00")
+	}
+
+	var buf strings.Builder
+	if err := j.Format(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `2021-01-03 Buy stock
+  assets:stock  10 AAPL @ $150.00
+  assets:cash
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestParseTotalPriceClause(t *testing.T) {
+	const input = `2021-01-03 Exchange
+    assets:eur  $100 @@ €92
+    assets:usd
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := j.Transactions[0].Postings[0]
+	if p.PriceClause != "@@ €92" || p.Amount == nil || p.Amount.Quantity != "100" {
+		t.Fatalf("unexpected posting: %+v", p)
+	}
+}
+
+func TestParseAssertion(t *testing.T) {
+	const input = `2021-01-01 Opening balance
+    assets:cash           100,00 € = 100,00 €
+    equity:opening
+`
+
+	j, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := j.Transactions[0].Postings[0]
+	if p.AssertionOp != "=" || p.AssertedAmount == nil || p.AssertedAmount.Quantity != "100,00" {
+		t.Fatalf("unexpected posting: %+v", p)
+	}
+}
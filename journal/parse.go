@@ -0,0 +1,319 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	reTransactionHeader = regexp.MustCompile(`^(\d\S*)(?:=(\S+))?\s*(\*|!)?\s*(?:\(([^)]*)\))?\s*(.*)$`)
+	rePostingLine       = regexp.MustCompile(`^\s+(\*|!)?\s*(.+)$`)
+	reFieldSep          = regexp.MustCompile(`  +|\t+`)
+	reAssertion         = regexp.MustCompile(`^(==?)\s*(.+)$`)
+	reAmount            = regexp.MustCompile(`^([^\d\s.,+-]*)\s*([+-]?[\d.,]+)\s*([^\d\s.,+-]*)$`)
+)
+
+// Parse reads an hledger journal from r and returns its parsed
+// representation.
+func Parse(r io.Reader) (*Journal, error) {
+	p := &parser{scan: bufio.NewScanner(r), styles: map[CommoditySymbol]AmountStyle{}}
+	return p.parse()
+}
+
+type parser struct {
+	scan    *bufio.Scanner
+	line    string
+	lineNr  int
+	styles  map[CommoditySymbol]AmountStyle
+	journal Journal
+
+	// pendingComments buffers standalone comment lines until it's known
+	// whether they lead directly into a transaction (and so become its
+	// LeadingComments) or stand on their own.
+	pendingComments []string
+}
+
+func (p *parser) flushPendingComments() {
+	for _, c := range p.pendingComments {
+		p.journal.Items = append(p.journal.Items, JournalItem{Comment: c})
+	}
+	p.pendingComments = nil
+}
+
+func (p *parser) parse() (*Journal, error) {
+	for p.scan.Scan() {
+		p.lineNr++
+		p.line = p.scan.Text()
+
+		switch {
+		case strings.TrimSpace(p.line) == "":
+			p.flushPendingComments()
+			p.journal.Items = append(p.journal.Items, JournalItem{Blank: true})
+		case strings.HasPrefix(strings.TrimSpace(p.line), ";"):
+			p.pendingComments = append(p.pendingComments, p.line)
+		case reTransactionHeader.MatchString(p.line):
+			txn, err := p.parseTransaction()
+			if err != nil {
+				return nil, err
+			}
+			txn.LeadingComments = p.pendingComments
+			p.pendingComments = nil
+			p.journal.Items = append(p.journal.Items, JournalItem{Transaction: txn})
+			p.journal.Transactions = append(p.journal.Transactions, *txn)
+			// parseTransaction consumes the blank line that ends it, if any
+			if strings.TrimSpace(p.line) == "" {
+				p.journal.Items = append(p.journal.Items, JournalItem{Blank: true})
+			}
+		default:
+			p.flushPendingComments()
+			d, err := p.parseDirective(p.line)
+			if err != nil {
+				return nil, err
+			}
+			if d.Kind == DirectiveCommodity {
+				p.applyCommodityDirective(d.Args)
+			}
+			p.journal.Items = append(p.journal.Items, JournalItem{Directive: d})
+			p.journal.Directives = append(p.journal.Directives, *d)
+		}
+	}
+	p.flushPendingComments()
+
+	if err := p.scan.Err(); err != nil {
+		return nil, err
+	}
+	return &p.journal, nil
+}
+
+func (p *parser) parseDirective(line string) (*Directive, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("journal:%d: empty directive", p.lineNr)
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	switch fields[0] {
+	case "include":
+		return &Directive{Kind: DirectiveInclude, Args: rest}, nil
+	case "account":
+		return &Directive{Kind: DirectiveAccount, Args: rest}, nil
+	case "alias":
+		return &Directive{Kind: DirectiveAlias, Args: rest}, nil
+	case "commodity":
+		return &Directive{Kind: DirectiveCommodity, Args: rest}, nil
+	case "D":
+		return &Directive{Kind: DirectiveDefaultCommodity, Args: rest}, nil
+	case "P":
+		return &Directive{Kind: DirectiveMarketPrice, Args: rest}, nil
+	case "Y":
+		return &Directive{Kind: DirectiveDefaultYear, Args: rest}, nil
+	default:
+		return nil, fmt.Errorf("journal:%d: unexpected line: %s", p.lineNr, line)
+	}
+}
+
+// applyCommodityDirective seeds p.styles from a `commodity` directive's
+// example amount, e.g. "commodity $1,000.00", so that amounts written after
+// it use the directive's style rather than whatever the first amount
+// happens to look like.
+func (p *parser) applyCommodityDirective(args string) {
+	commodity, _, style, ok := parseAmountLiteral(strings.TrimSpace(args))
+	if !ok || commodity == "" {
+		return
+	}
+	p.styles[commodity] = style
+}
+
+func (p *parser) parseTransaction() (*Transaction, error) {
+	m := reTransactionHeader.FindStringSubmatch(p.line)
+	if m == nil {
+		return nil, fmt.Errorf("journal:%d: malformed transaction header: %s", p.lineNr, p.line)
+	}
+
+	txn := &Transaction{
+		Date:   m[1],
+		Date2:  m[2],
+		Status: m[3],
+		Code:   m[4],
+	}
+
+	desc := m[5]
+	var headerComments []string
+	if i := strings.Index(desc, ";"); i >= 0 {
+		if c := strings.TrimSpace(desc[i+1:]); c != "" {
+			headerComments = append(headerComments, c)
+		}
+		desc = desc[:i]
+	}
+	if i := strings.Index(desc, "|"); i >= 0 {
+		txn.Note = strings.TrimSpace(desc[i+1:])
+		desc = desc[:i]
+	}
+	txn.Description = strings.TrimSpace(desc)
+
+	for p.scan.Scan() {
+		p.lineNr++
+		p.line = p.scan.Text()
+
+		if strings.TrimSpace(p.line) == "" {
+			break
+		}
+		if !rePostingLine.MatchString(p.line) {
+			return nil, fmt.Errorf("journal:%d: expected posting, got: %s", p.lineNr, p.line)
+		}
+
+		if trimmed := strings.TrimSpace(p.line); strings.HasPrefix(trimmed, ";") {
+			comment := strings.TrimSpace(strings.TrimPrefix(trimmed, ";"))
+			if len(txn.Postings) == 0 {
+				headerComments = append(headerComments, comment)
+			} else {
+				last := &txn.Postings[len(txn.Postings)-1]
+				last.TrailingComment = joinComment(last.TrailingComment, comment)
+			}
+			continue
+		}
+
+		posting, err := p.parsePosting(p.line)
+		if err != nil {
+			return nil, err
+		}
+		txn.Postings = append(txn.Postings, *posting)
+	}
+
+	txn.HeaderComment = strings.Join(headerComments, "\n")
+
+	return txn, nil
+}
+
+// joinComment appends next to comment, separated by a newline if comment is
+// non-empty.
+func joinComment(comment, next string) string {
+	if comment == "" {
+		return next
+	}
+	return comment + "\n" + next
+}
+
+func (p *parser) parsePosting(line string) (*Posting, error) {
+	m := rePostingLine.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("journal:%d: malformed posting: %s", p.lineNr, line)
+	}
+
+	posting := &Posting{Status: m[1]}
+	rest := m[2]
+
+	// Split off the inline trailing comment, if any
+	if i := strings.Index(rest, ";"); i >= 0 {
+		posting.TrailingComment = strings.TrimSpace(rest[i+1:])
+		rest = rest[:i]
+	}
+	rest = strings.TrimRight(rest, " \t")
+
+	parts := reFieldSep.Split(rest, 2)
+	posting.Account = strings.TrimSpace(parts[0])
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		return posting, nil
+	}
+
+	amountField := strings.TrimSpace(parts[1])
+
+	// Split off a balance assertion, "= AMOUNT" or "== AMOUNT"
+	if i := strings.IndexAny(amountField, "="); i >= 0 {
+		assertion := reAssertion.FindStringSubmatch(strings.TrimSpace(amountField[i:]))
+		if assertion != nil {
+			amt, err := p.parseAmount(assertion[2])
+			if err != nil {
+				return nil, err
+			}
+			posting.AssertionOp = assertion[1]
+			posting.AssertedAmount = amt
+			amountField = strings.TrimSpace(amountField[:i])
+		}
+	}
+
+	// Split off a unit/total price clause, "@ AMOUNT" or "@@ AMOUNT", and
+	// keep it verbatim rather than parsing it.
+	if i := strings.IndexByte(amountField, '@'); i >= 0 {
+		posting.PriceClause = strings.TrimSpace(amountField[i:])
+		amountField = strings.TrimSpace(amountField[:i])
+	}
+
+	if amountField != "" {
+		amt, err := p.parseAmount(amountField)
+		if err != nil {
+			return nil, err
+		}
+		posting.Amount = amt
+	}
+
+	return posting, nil
+}
+
+func (p *parser) parseAmount(s string) (*Amount, error) {
+	s = strings.TrimSpace(s)
+	commodity, quantity, style, ok := parseAmountLiteral(s)
+	if !ok {
+		return nil, fmt.Errorf("journal:%d: malformed amount: %s", p.lineNr, s)
+	}
+
+	if existing, ok := p.styles[commodity]; ok {
+		style = existing
+	} else if commodity != "" {
+		p.styles[commodity] = style
+	}
+
+	return &Amount{Commodity: commodity, Quantity: quantity, Style: style}, nil
+}
+
+// parseAmountLiteral parses a single amount, e.g. "$1,000.00", into its
+// commodity, numeric literal and inferred AmountStyle. It does not consult
+// or update a parser's known styles, so it's also used to seed a style from
+// a `commodity` directive's example amount.
+func parseAmountLiteral(s string) (commodity CommoditySymbol, quantity string, style AmountStyle, ok bool) {
+	m := reAmount.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", AmountStyle{}, false
+	}
+
+	symbol := m[1]
+	symbolLeft := symbol != ""
+	if !symbolLeft {
+		symbol = m[3]
+	}
+
+	style = inferStyle(m[2])
+	style.SymbolLeft = symbolLeft
+	style.SymbolSpaced = strings.Contains(s, symbol+" ") || strings.Contains(s, " "+symbol)
+
+	return CommoditySymbol(symbol), m[2], style, true
+}
+
+// inferStyle derives an AmountStyle's decimal/digit-group characters and
+// precision from a numeric literal as written, e.g. "1,234.56" or "1.234,56".
+func inferStyle(quantity string) AmountStyle {
+	lastComma := strings.LastIndexByte(quantity, ',')
+	lastDot := strings.LastIndexByte(quantity, '.')
+
+	style := AmountStyle{DecimalChar: '.'}
+	switch {
+	case lastComma > lastDot:
+		style.DecimalChar = ','
+		if lastDot >= 0 {
+			style.DigitGroupChar = '.'
+		}
+		style.Precision = len(quantity) - lastComma - 1
+	case lastDot > lastComma:
+		style.DecimalChar = '.'
+		if lastComma >= 0 {
+			style.DigitGroupChar = ','
+		}
+		style.Precision = len(quantity) - lastDot - 1
+	default:
+		style.Precision = 0
+	}
+	return style
+}
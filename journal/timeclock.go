@@ -0,0 +1,123 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timestampLayout is the "YYYY-MM-DD HH:MM:SS" layout used by timeclock
+// entries.
+const timestampLayout = "2006-01-02 15:04:05"
+
+var reTimeclock = regexp.MustCompile(`^([io])\s+(\S+\s+\S+)(?:\s+(.*))?$`)
+
+// TimeclockEntry is a single clock-in ("i") or clock-out ("o") line from a
+// timeclock file, as read by hledger's TimeclockReader.
+type TimeclockEntry struct {
+	Type      string // "i" or "o"
+	Timestamp string // "YYYY-MM-DD HH:MM:SS" as written
+	Account   string // set for "i" entries, empty for "o"
+}
+
+// ParseTimeclock reads timeclock entries from r.
+func ParseTimeclock(r io.Reader) ([]TimeclockEntry, error) {
+	var entries []TimeclockEntry
+	scan := bufio.NewScanner(r)
+	lineNr := 0
+	for scan.Scan() {
+		lineNr++
+		line := scan.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), ";") {
+			continue
+		}
+
+		m := reTimeclock.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("timeclock:%d: malformed entry: %s", lineNr, line)
+		}
+		entries = append(entries, TimeclockEntry{Type: m[1], Timestamp: m[2], Account: strings.TrimSpace(m[3])})
+	}
+	return entries, scan.Err()
+}
+
+// TimeclockAutoclose controls how TimeclockFormatter handles a session left
+// open (an "i" with no matching "o") at end-of-file.
+type TimeclockAutoclose string
+
+// The autoclose policies accepted by the --timeclock-autoclose flag.
+const (
+	TimeclockAutocloseEOF      TimeclockAutoclose = "eof"
+	TimeclockAutocloseMidnight TimeclockAutoclose = "midnight"
+	TimeclockAutocloseNone     TimeclockAutoclose = "none"
+)
+
+// TimeclockFormatter canonicalizes a timeclock file: it aligns the "i"/"o"
+// timestamp column and, per Autoclose, closes a trailing unclosed session.
+type TimeclockFormatter struct {
+	Autoclose TimeclockAutoclose
+	// Now returns the current time, used by the "eof" policy to close a
+	// trailing unclosed session at the moment hledger-fmt is run. Defaults
+	// to time.Now when nil.
+	Now func() time.Time
+}
+
+func (f TimeclockFormatter) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// Format implements Formatter.
+func (f TimeclockFormatter) Format(r io.Reader, w io.Writer) error {
+	entries, err := ParseTimeclock(r)
+	if err != nil {
+		return err
+	}
+	entries = closeTimeclock(entries, f.Autoclose, f.now())
+
+	width := 0
+	for _, e := range entries {
+		if len(e.Timestamp) > width {
+			width = len(e.Timestamp)
+		}
+	}
+
+	for _, e := range entries {
+		line := e.Type + " " + padRight(e.Timestamp, width)
+		if e.Account != "" {
+			line += " " + e.Account
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeTimeclock appends a synthetic "o" entry when entries ends with an
+// unclosed "i" session and autoclose requires one. now is the instant the
+// "eof" policy closes the session at.
+func closeTimeclock(entries []TimeclockEntry, autoclose TimeclockAutoclose, now time.Time) []TimeclockEntry {
+	if len(entries) == 0 || entries[len(entries)-1].Type != "i" {
+		return entries
+	}
+
+	last := entries[len(entries)-1]
+	switch autoclose {
+	case TimeclockAutocloseMidnight:
+		day := last.Timestamp
+		if i := strings.IndexByte(day, ' '); i >= 0 {
+			day = day[:i]
+		}
+		return append(entries, TimeclockEntry{Type: "o", Timestamp: day + " 23:59:59"})
+	case TimeclockAutocloseNone:
+		return entries
+	default: // TimeclockAutocloseEOF
+		return append(entries, TimeclockEntry{Type: "o", Timestamp: now.Format(timestampLayout)})
+	}
+}
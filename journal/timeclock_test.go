@@ -0,0 +1,68 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeclockAutocloseEOF(t *testing.T) {
+	const input = `i 2021-01-04 08:00:00 work:acme
+o 2021-01-04 16:30:00
+i 2021-01-05 08:15:00 work:acme
+`
+
+	var buf strings.Builder
+	f := TimeclockFormatter{
+		Autoclose: TimeclockAutocloseEOF,
+		Now:       func() time.Time { return time.Date(2021, 1, 5, 9, 30, 0, 0, time.UTC) },
+	}
+	if err := f.Format(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The trailing unclosed "i" session is closed at Now, not at its own
+	// timestamp, so the session keeps a real duration instead of being
+	// zeroed out.
+	const expected = `i 2021-01-04 08:00:00 work:acme
+o 2021-01-04 16:30:00
+i 2021-01-05 08:15:00 work:acme
+o 2021-01-05 09:30:00
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestTimeclockAutocloseMidnight(t *testing.T) {
+	const input = `i 2021-01-05 08:15:00 work:acme
+`
+
+	var buf strings.Builder
+	f := TimeclockFormatter{Autoclose: TimeclockAutocloseMidnight}
+	if err := f.Format(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `i 2021-01-05 08:15:00 work:acme
+o 2021-01-05 23:59:59
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestTimeclockAutocloseNone(t *testing.T) {
+	const input = `i 2021-01-04 08:00:00 work:acme
+`
+
+	var buf strings.Builder
+	f := TimeclockFormatter{Autoclose: TimeclockAutocloseNone}
+	if err := f.Format(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != input {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, input)
+	}
+}
@@ -0,0 +1,132 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reTimedotEntry = regexp.MustCompile(`^(\S(?:.*\S)?)\s{2,}(.+)$`)
+
+// TimedotEntry is a single account/quantity line within a timedot day, as
+// read by hledger's TimedotReader.
+type TimedotEntry struct {
+	Account  string
+	Quantity string // the dots or decimal number, exactly as written
+}
+
+// TimedotLine is one line of a timedot file: a date header, a blank line, a
+// standalone comment, or an account/quantity Entry. Exactly one of Date,
+// Blank, Raw or Entry applies.
+type TimedotLine struct {
+	Date  string
+	Blank bool
+	Raw   string
+	Entry *TimedotEntry
+}
+
+// ParseTimedot reads timedot lines from r.
+func ParseTimedot(r io.Reader) ([]TimedotLine, error) {
+	var lines []TimedotLine
+	scan := bufio.NewScanner(r)
+	lineNr := 0
+	for scan.Scan() {
+		lineNr++
+		line := scan.Text()
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			lines = append(lines, TimedotLine{Blank: true})
+		case strings.HasPrefix(strings.TrimSpace(line), ";"):
+			lines = append(lines, TimedotLine{Raw: line})
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			lines = append(lines, TimedotLine{Date: strings.TrimSpace(line)})
+		default:
+			m := reTimedotEntry.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				return nil, fmt.Errorf("timedot:%d: malformed entry: %s", lineNr, line)
+			}
+			lines = append(lines, TimedotLine{Entry: &TimedotEntry{Account: m[1], Quantity: strings.TrimSpace(m[2])}})
+		}
+	}
+	return lines, scan.Err()
+}
+
+// TimedotStyle controls how TimedotFormatter renders a timedot entry's
+// quantity column.
+type TimedotStyle string
+
+// The quantity styles accepted by the --timedot-style flag.
+const (
+	TimedotStyleDots    TimedotStyle = "dots"
+	TimedotStyleDecimal TimedotStyle = "decimal"
+)
+
+// TimedotFormatter canonicalizes a timedot file: it aligns the account
+// column and normalizes the quantity column to Style.
+type TimedotFormatter struct {
+	Style TimedotStyle
+}
+
+// Format implements Formatter.
+func (f TimedotFormatter) Format(r io.Reader, w io.Writer) error {
+	lines, err := ParseTimedot(r)
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, l := range lines {
+		if l.Entry != nil && len(l.Entry.Account) > width {
+			width = len(l.Entry.Account)
+		}
+	}
+
+	for _, l := range lines {
+		switch {
+		case l.Entry != nil:
+			qty := normalizeTimedotQuantity(l.Entry.Quantity, f.Style)
+			if _, err := fmt.Fprintln(w, padRight(l.Entry.Account, width)+"  "+qty); err != nil {
+				return err
+			}
+		case l.Blank:
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		case l.Date != "":
+			if _, err := fmt.Fprintln(w, l.Date); err != nil {
+				return err
+			}
+		default:
+			if _, err := fmt.Fprintln(w, l.Raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeTimedotQuantity converts a timedot quantity between the dotted
+// (one dot = a quarter hour) and decimal-hours representations.
+func normalizeTimedotQuantity(raw string, style TimedotStyle) string {
+	hours := timedotHours(raw)
+
+	if style == TimedotStyleDecimal {
+		return strconv.FormatFloat(hours, 'f', -1, 64)
+	}
+
+	dots := int(hours/0.25 + 0.5)
+	return strings.Repeat(".", dots)
+}
+
+func timedotHours(raw string) float64 {
+	if n, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64); err == nil {
+		return n
+	}
+
+	dots := strings.Count(raw, ".")
+	return float64(dots) * 0.25
+}
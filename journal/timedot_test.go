@@ -0,0 +1,48 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTimedotFormatDots(t *testing.T) {
+	const input = `2021-01-04
+  work:acme  ....
+  admin  ..
+`
+
+	var buf strings.Builder
+	f := TimedotFormatter{Style: TimedotStyleDots}
+	if err := f.Format(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `2021-01-04
+work:acme  ....
+admin      ..
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}
+
+func TestTimedotFormatDecimal(t *testing.T) {
+	const input = `2021-01-04
+  work:acme  ....
+  admin  ..
+`
+
+	var buf strings.Builder
+	f := TimedotFormatter{Style: TimedotStyleDecimal}
+	if err := f.Format(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `2021-01-04
+work:acme  1
+admin      0.5
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Got:\n%q\nExpected:\n%q\n", got, expected)
+	}
+}